@@ -0,0 +1,226 @@
+package fasttext
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+
+	"github.com/justinfargnoli/go-fasttext/ann"
+)
+
+// Neighbor is a word and its similarity score to some query embedding,
+// as returned by MostSimilarK and MostSimilarWord.
+type Neighbor struct {
+	Word  string
+	Score float64
+}
+
+// BuildANN builds a Hierarchical Navigable Small World graph (see package
+// ann) over the embeddings already loaded by Build, and persists it in
+// the database so it does not need to be rebuilt on every process start.
+// It must be called after Build, and again after Build is used to load
+// additional embeddings.
+func (ft *FastText) BuildANN(cfg ann.Config) error {
+	rows, err := ft.db.Query(`SELECT rowid, word, emb FROM fasttext;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	graph := ann.New(cfg)
+	words := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var word string
+		var binVec []byte
+		if err := rows.Scan(&id, &word, &binVec); err != nil {
+			return err
+		}
+		vec, err := bytesToVec(binVec, ByteOrder)
+		if err != nil {
+			return err
+		}
+		graph.Insert(id, vec)
+		words[id] = word
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := ft.persistANN(graph, words, cfg); err != nil {
+		return err
+	}
+
+	ft.ann = graph
+	ft.annWords = words
+	return nil
+}
+
+func (ft *FastText) persistANN(graph *ann.Graph, words map[int]string, cfg ann.Config) error {
+	if _, err := ft.db.Exec(`DROP TABLE IF EXISTS fasttext_ann_nodes;`); err != nil {
+		return err
+	}
+	if _, err := ft.db.Exec(`
+	CREATE TABLE fasttext_ann_nodes(
+		id INTEGER PRIMARY KEY,
+		links BLOB
+	);`); err != nil {
+		return err
+	}
+	if _, err := ft.db.Exec(`DROP TABLE IF EXISTS fasttext_ann_meta;`); err != nil {
+		return err
+	}
+	if _, err := ft.db.Exec(`
+	CREATE TABLE fasttext_ann_meta(
+		entry_point INTEGER,
+		m INTEGER,
+		ef_construction INTEGER,
+		ef_search INTEGER
+	);`); err != nil {
+		return err
+	}
+
+	stmt, err := ft.db.Prepare(`INSERT INTO fasttext_ann_nodes(id, links) VALUES(?, ?);`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for id := range words {
+		links := make([][]int, graph.Level(id)+1)
+		for l := range links {
+			links[l] = graph.Links(id, l)
+		}
+		if _, err := stmt.Exec(id, encodeLinks(links)); err != nil {
+			return err
+		}
+	}
+
+	_, err = ft.db.Exec(`INSERT INTO fasttext_ann_meta(entry_point, m, ef_construction, ef_search) VALUES(?, ?, ?, ?);`,
+		graph.EntryPoint(), cfg.M, cfg.EfConstruction, cfg.EfSearch)
+	return err
+}
+
+// loadANN returns the in-memory ANN graph, rebuilding it lazily from the
+// fasttext_ann_* tables written by BuildANN on first use.
+func (ft *FastText) loadANN() (*ann.Graph, map[int]string, error) {
+	if ft.ann != nil {
+		return ft.ann, ft.annWords, nil
+	}
+
+	var entryPoint, m, efConstruction, efSearch int
+	err := ft.db.QueryRow(`SELECT entry_point, m, ef_construction, ef_search FROM fasttext_ann_meta;`).
+		Scan(&entryPoint, &m, &efConstruction, &efSearch)
+	if err == sql.ErrNoRows {
+		return nil, nil, errors.New("fasttext: ANN index not built, call BuildANN first")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := ft.db.Query(`
+	SELECT n.id, f.word, f.emb, n.links
+	FROM fasttext_ann_nodes n JOIN fasttext f ON f.rowid = n.id;`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	nodes := make(map[int]ann.RestoredNode)
+	words := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var word string
+		var binVec, binLinks []byte
+		if err := rows.Scan(&id, &word, &binVec, &binLinks); err != nil {
+			return nil, nil, err
+		}
+		vec, err := bytesToVec(binVec, ByteOrder)
+		if err != nil {
+			return nil, nil, err
+		}
+		links, err := decodeLinks(binLinks)
+		if err != nil {
+			return nil, nil, err
+		}
+		nodes[id] = ann.RestoredNode{Vector: vec, Links: links}
+		words[id] = word
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := ann.Config{M: m, EfConstruction: efConstruction, EfSearch: efSearch}
+	graph := ann.Restore(cfg, entryPoint, nodes)
+
+	ft.ann = graph
+	ft.annWords = words
+	return graph, words, nil
+}
+
+// MostSimilarK returns the k embeddings nearest to query, using the
+// persisted HNSW index built by BuildANN.
+func (ft *FastText) MostSimilarK(query []float64, k int) ([]Neighbor, error) {
+	graph, words, err := ft.loadANN()
+	if err != nil {
+		return nil, err
+	}
+
+	result := graph.Search(query, k)
+	neighbors := make([]Neighbor, len(result))
+	for i, n := range result {
+		neighbors[i] = Neighbor{Word: words[n.ID], Score: n.Score}
+	}
+	return neighbors, nil
+}
+
+// MostSimilarWord returns the k words whose embeddings are nearest to the
+// embedding of word, using the persisted HNSW index built by BuildANN.
+func (ft *FastText) MostSimilarWord(word string, k int) ([]Neighbor, error) {
+	query, err := ft.EmbeddingVector(word)
+	if err != nil {
+		return nil, err
+	}
+	return ft.MostSimilarK(query, k)
+}
+
+// encodeLinks serializes an HNSW node's per-layer adjacency lists into a
+// byte slice, ready to be stored as a BLOB in SQLite3.
+func encodeLinks(links [][]int) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, ByteOrder, int32(len(links)))
+	for _, layer := range links {
+		binary.Write(buf, ByteOrder, int32(len(layer)))
+		for _, id := range layer {
+			binary.Write(buf, ByteOrder, int32(id))
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeLinks deserializes a byte slice produced by encodeLinks back into
+// an HNSW node's per-layer adjacency lists.
+func decodeLinks(b []byte) ([][]int, error) {
+	reader := bytes.NewReader(b)
+	var numLayers int32
+	if err := binary.Read(reader, ByteOrder, &numLayers); err != nil {
+		return nil, err
+	}
+	links := make([][]int, numLayers)
+	for l := range links {
+		var count int32
+		if err := binary.Read(reader, ByteOrder, &count); err != nil {
+			return nil, err
+		}
+		layer := make([]int, count)
+		for i := range layer {
+			var id int32
+			if err := binary.Read(reader, ByteOrder, &id); err != nil {
+				return nil, err
+			}
+			layer[i] = int(id)
+		}
+		links[l] = layer
+	}
+	return links, nil
+}
@@ -0,0 +1,413 @@
+// Package ann implements a Hierarchical Navigable Small World (HNSW) graph
+// for approximate nearest-neighbor search over dense vectors.
+//
+// A Graph only knows about caller-assigned integer IDs and the vectors
+// associated with them; it does not know about words, databases, or any
+// other detail specific to fastText embeddings. That lets it be built,
+// searched, and persisted independently of the fasttext package.
+package ann
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Neighbor is a single result from a nearest-neighbor search, identified
+// by the ID that was passed to Insert. Score is 1 minus the cosine
+// distance, so a larger Score means a closer match.
+type Neighbor struct {
+	ID    int
+	Score float64
+}
+
+// Config holds the tunables of an HNSW graph.
+type Config struct {
+	// M is the number of bidirectional links created per inserted element
+	// at every layer above layer 0 (layer 0 uses M0 = 2*M).
+	M int
+	// EfConstruction is the size of the dynamic candidate list used while
+	// building the graph; higher values trade build time for recall.
+	EfConstruction int
+	// EfSearch is the size of the dynamic candidate list used while
+	// searching; higher values trade query time for recall.
+	EfSearch int
+}
+
+// DefaultConfig returns the tunables recommended by the original HNSW
+// paper (Malkov & Yashunin) for general-purpose use.
+func DefaultConfig() Config {
+	return Config{M: 16, EfConstruction: 200, EfSearch: 64}
+}
+
+// Graph is an in-memory HNSW graph over a set of vectors, each identified
+// by a caller-assigned integer ID.
+//
+// Graph is not safe for concurrent use.
+type Graph struct {
+	cfg Config
+	mL  float64 // level-generation normalization factor, 1/ln(M)
+
+	vectors map[int][]float64
+	levels  map[int]int
+	links   map[int][][]int // links[id][layer] = neighbor IDs at that layer
+
+	entryPoint int
+	maxLevel   int
+	rnd        *rand.Rand
+}
+
+// New creates an empty graph using the given configuration. Zero-valued
+// fields in cfg fall back to DefaultConfig.
+func New(cfg Config) *Graph {
+	def := DefaultConfig()
+	if cfg.M <= 0 {
+		cfg.M = def.M
+	}
+	if cfg.EfConstruction <= 0 {
+		cfg.EfConstruction = def.EfConstruction
+	}
+	if cfg.EfSearch <= 0 {
+		cfg.EfSearch = def.EfSearch
+	}
+	return &Graph{
+		cfg:        cfg,
+		mL:         1 / math.Log(float64(cfg.M)),
+		vectors:    make(map[int][]float64),
+		levels:     make(map[int]int),
+		links:      make(map[int][][]int),
+		entryPoint: -1,
+		maxLevel:   -1,
+		rnd:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Len returns the number of vectors inserted into the graph.
+func (g *Graph) Len() int {
+	return len(g.vectors)
+}
+
+// EntryPoint returns the ID of the graph's top-layer entry point, or -1
+// if the graph is empty.
+func (g *Graph) EntryPoint() int {
+	return g.entryPoint
+}
+
+// Level returns the highest layer the given ID was inserted into.
+func (g *Graph) Level(id int) int {
+	return g.levels[id]
+}
+
+// Links returns the adjacency list for id at layer, or nil if id has no
+// links at that layer.
+func (g *Graph) Links(id, layer int) []int {
+	return g.neighborsAt(id, layer)
+}
+
+// Insert adds vec to the graph under the given ID. IDs must be unique;
+// inserting the same ID twice produces an inconsistent graph.
+func (g *Graph) Insert(id int, vec []float64) {
+	level := g.randomLevel()
+	g.vectors[id] = vec
+	g.levels[id] = level
+	g.links[id] = make([][]int, level+1)
+
+	if g.entryPoint == -1 {
+		g.entryPoint = id
+		g.maxLevel = level
+		return
+	}
+
+	entry := g.entryPoint
+	for l := g.maxLevel; l > level; l-- {
+		entry = g.greedyClosest(vec, entry, l)
+	}
+
+	for l := min(level, g.maxLevel); l >= 0; l-- {
+		m := g.cfg.M
+		if l == 0 {
+			m = g.cfg.M * 2
+		}
+		candidates := g.searchLayer(vec, entry, g.cfg.EfConstruction, l)
+		neighbors := g.selectNeighbors(candidates, m)
+		g.links[id][l] = neighbors
+		for _, n := range neighbors {
+			g.addLink(n, id, l, m)
+		}
+		if len(candidates) > 0 {
+			entry = candidates[0].id
+		}
+	}
+
+	if level > g.maxLevel {
+		g.maxLevel = level
+		g.entryPoint = id
+	}
+}
+
+// Search returns up to k neighbors of query, ordered by decreasing Score.
+// A non-positive k returns no neighbors.
+func (g *Graph) Search(query []float64, k int) []Neighbor {
+	if g.entryPoint == -1 || k <= 0 {
+		return nil
+	}
+
+	entry := g.entryPoint
+	for l := g.maxLevel; l > 0; l-- {
+		entry = g.greedyClosest(query, entry, l)
+	}
+
+	ef := g.cfg.EfSearch
+	if ef < k {
+		ef = k
+	}
+	candidates := g.searchLayer(query, entry, ef, 0)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	neighbors := make([]Neighbor, len(candidates))
+	for i, c := range candidates {
+		neighbors[i] = Neighbor{ID: c.id, Score: 1 - c.dist}
+	}
+	return neighbors
+}
+
+// RestoredNode is the persisted state of a single graph node, as produced
+// by a caller that serialized a Graph built with Insert.
+type RestoredNode struct {
+	Vector []float64
+	Links  [][]int // Links[layer] = neighbor IDs at that layer
+}
+
+// Restore rebuilds a Graph directly from previously persisted nodes,
+// bypassing the random level assignment and neighbor-selection heuristic
+// that Insert performs. Callers that persist a Graph built with Insert
+// use this to load it back without repeating the (expensive) build.
+func Restore(cfg Config, entryPoint int, nodes map[int]RestoredNode) *Graph {
+	g := New(cfg)
+	g.entryPoint = entryPoint
+	for id, n := range nodes {
+		g.vectors[id] = n.Vector
+		g.levels[id] = len(n.Links) - 1
+		g.links[id] = n.Links
+		if g.levels[id] > g.maxLevel {
+			g.maxLevel = g.levels[id]
+		}
+	}
+	return g
+}
+
+func (g *Graph) randomLevel() int {
+	return int(math.Floor(-math.Log(g.rnd.Float64()) * g.mL))
+}
+
+func (g *Graph) neighborsAt(id, layer int) []int {
+	links := g.links[id]
+	if layer >= len(links) {
+		return nil
+	}
+	return links[layer]
+}
+
+// candidate pairs a node ID with its distance to some query vector.
+type candidate struct {
+	id   int
+	dist float64
+}
+
+// greedyClosest performs a single-best greedy descent from entry towards
+// query within one layer, used to find a good entry point for the layer
+// below.
+func (g *Graph) greedyClosest(query []float64, entry int, layer int) int {
+	best := entry
+	bestDist := g.distance(query, g.vectors[entry])
+	for improved := true; improved; {
+		improved = false
+		for _, nb := range g.neighborsAt(best, layer) {
+			if d := g.distance(query, g.vectors[nb]); d < bestDist {
+				bestDist = d
+				best = nb
+				improved = true
+			}
+		}
+	}
+	return best
+}
+
+// searchLayer performs a best-first search within one layer, expanding
+// from entry, and returns up to ef candidates ordered by increasing
+// distance to query.
+//
+// toExplore is a min-heap (closest unexplored candidate first) and found
+// is a max-heap of the best ef candidates seen so far (farthest, i.e.
+// weakest, at the root), so both the next node to expand and the current
+// worst kept candidate are available in O(log ef) instead of re-sorting
+// the whole candidate set on every expansion.
+func (g *Graph) searchLayer(query []float64, entry int, ef int, layer int) []candidate {
+	entryDist := g.distance(query, g.vectors[entry])
+	visited := map[int]bool{entry: true}
+
+	toExplore := &minCandidateHeap{{entry, entryDist}}
+	found := &maxCandidateHeap{{entry, entryDist}}
+
+	for toExplore.Len() > 0 {
+		c := heap.Pop(toExplore).(candidate)
+
+		if found.Len() >= ef && c.dist > (*found)[0].dist {
+			break
+		}
+
+		for _, nb := range g.neighborsAt(c.id, layer) {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+
+			d := g.distance(query, g.vectors[nb])
+			worst := math.Inf(1)
+			if found.Len() >= ef {
+				worst = (*found)[0].dist
+			}
+			if found.Len() < ef || d < worst {
+				heap.Push(toExplore, candidate{nb, d})
+				heap.Push(found, candidate{nb, d})
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	result := make([]candidate, found.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(found).(candidate)
+	}
+	return result
+}
+
+// minCandidateHeap is a min-heap of candidate ordered by increasing
+// distance, used to always expand the closest unvisited node next.
+type minCandidateHeap []candidate
+
+func (h minCandidateHeap) Len() int            { return len(h) }
+func (h minCandidateHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h minCandidateHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minCandidateHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minCandidateHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// maxCandidateHeap is a max-heap of candidate ordered by decreasing
+// distance, so the weakest of the ef candidates kept so far is always at
+// the root and can be evicted in O(log ef) when a closer candidate is
+// found.
+type maxCandidateHeap []candidate
+
+func (h maxCandidateHeap) Len() int            { return len(h) }
+func (h maxCandidateHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h maxCandidateHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxCandidateHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxCandidateHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// selectNeighbors implements the HNSW neighbor-selection heuristic: a
+// candidate is kept only if it is closer to the inserted point than to
+// any neighbor already selected, which favors links that spread across
+// the space rather than clustering on one side of it. If the heuristic
+// keeps fewer than m candidates, the closest remaining ones are used to
+// fill the budget so nodes never end up under-connected.
+func (g *Graph) selectNeighbors(candidates []candidate, m int) []int {
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+
+	var selected []int
+	var selectedVecs [][]float64
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		cVec := g.vectors[c.id]
+		keep := true
+		for _, sVec := range selectedVecs {
+			if g.distance(cVec, sVec) < c.dist {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c.id)
+			selectedVecs = append(selectedVecs, cVec)
+		}
+	}
+
+	if len(selected) < m {
+		have := make(map[int]bool, len(selected))
+		for _, id := range selected {
+			have[id] = true
+		}
+		for _, c := range candidates {
+			if len(selected) >= m {
+				break
+			}
+			if !have[c.id] {
+				selected = append(selected, c.id)
+			}
+		}
+	}
+	return selected
+}
+
+// addLink adds a bidirectional link from neighborID to newID at layer,
+// pruning neighborID's adjacency list back down to maxM with the same
+// heuristic used during insertion if it grew past budget.
+func (g *Graph) addLink(neighborID, newID, layer, maxM int) {
+	links := g.links[neighborID]
+	for len(links) <= layer {
+		links = append(links, nil)
+	}
+	links[layer] = append(links[layer], newID)
+
+	if len(links[layer]) > maxM {
+		nbVec := g.vectors[neighborID]
+		candidates := make([]candidate, len(links[layer]))
+		for i, id := range links[layer] {
+			candidates[i] = candidate{id, g.distance(nbVec, g.vectors[id])}
+		}
+		links[layer] = g.selectNeighbors(candidates, maxM)
+	}
+	g.links[neighborID] = links
+}
+
+// distance returns the cosine distance (1 - cosine similarity) between
+// a and b. If a and b are L2-normalized, this reduces to 1 minus their
+// inner product.
+func (g *Graph) distance(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(na)*math.Sqrt(nb))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,75 @@
+package ann
+
+import "testing"
+
+func TestSearchFindsExactMatch(t *testing.T) {
+	g := New(Config{M: 8, EfConstruction: 64, EfSearch: 32})
+
+	vectors := map[int][]float64{
+		0: {1, 0, 0},
+		1: {0, 1, 0},
+		2: {0, 0, 1},
+		3: {0.9, 0.1, 0},
+		4: {-1, 0, 0},
+	}
+	for id := 0; id < 5; id++ {
+		g.Insert(id, vectors[id])
+	}
+
+	result := g.Search([]float64{1, 0, 0}, 2)
+	if len(result) != 2 {
+		t.Fatalf("expected 2 neighbors, got %d", len(result))
+	}
+	if result[0].ID != 0 {
+		t.Errorf("expected closest neighbor to be 0, got %d", result[0].ID)
+	}
+	if result[0].Score < result[1].Score {
+		t.Errorf("expected neighbors ordered by decreasing score, got %+v", result)
+	}
+}
+
+func TestSearchOnEmptyGraph(t *testing.T) {
+	g := New(DefaultConfig())
+	if result := g.Search([]float64{1, 2, 3}, 5); result != nil {
+		t.Errorf("expected nil result on empty graph, got %+v", result)
+	}
+}
+
+func TestSearchWithNonPositiveK(t *testing.T) {
+	g := New(Config{M: 8, EfConstruction: 64, EfSearch: 32})
+	g.Insert(0, []float64{1, 0, 0})
+
+	if result := g.Search([]float64{1, 0, 0}, 0); result != nil {
+		t.Errorf("expected nil result for k=0, got %+v", result)
+	}
+	if result := g.Search([]float64{1, 0, 0}, -1); result != nil {
+		t.Errorf("expected nil result for k=-1, got %+v", result)
+	}
+}
+
+func TestRestoreProducesEquivalentGraph(t *testing.T) {
+	g := New(Config{M: 8, EfConstruction: 64, EfSearch: 32})
+	vectors := map[int][]float64{
+		0: {1, 0, 0},
+		1: {0, 1, 0},
+		2: {0, 0, 1},
+	}
+	for id := 0; id < 3; id++ {
+		g.Insert(id, vectors[id])
+	}
+
+	nodes := make(map[int]RestoredNode, 3)
+	for id, vec := range vectors {
+		links := make([][]int, g.Level(id)+1)
+		for l := range links {
+			links[l] = g.Links(id, l)
+		}
+		nodes[id] = RestoredNode{Vector: vec, Links: links}
+	}
+
+	restored := Restore(g.cfg, g.EntryPoint(), nodes)
+	result := restored.Search([]float64{0, 1, 0}, 1)
+	if len(result) != 1 || result[0].ID != 1 {
+		t.Errorf("expected restored graph to find neighbor 1, got %+v", result)
+	}
+}
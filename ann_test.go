@@ -0,0 +1,64 @@
+package fasttext
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justinfargnoli/go-fasttext/ann"
+)
+
+// TestNewInMemoryCopiesANNIndex builds a database on disk, including its
+// ANN index and (when available) its VSS index, reopens it via
+// NewInMemory, and checks that MostSimilarK and SearchVSS still answer
+// queries. NewInMemory copies ordinary tables individually based on which
+// ones exist on disk, so an ANN table that BuildANN wrote but NewInMemory
+// forgot to copy would otherwise only surface as a confusing error deep
+// inside MostSimilarK on the in-memory copy. The vss_fasttext virtual
+// table is not copied, so SearchVSS on the in-memory copy answers through
+// its scan-based fallback.
+func TestNewInMemoryCopiesANNIndex(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "fasttext.db")
+
+	onDisk := New(dbPath)
+	if err := onDisk.BuildWithOptions(strings.NewReader(analogyVecFile), BuildOptions{L2Normalize: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := onDisk.BuildANN(ann.DefaultConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if err := onDisk.BuildVSSIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := onDisk.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	inMem := NewInMemory(dbPath)
+	defer inMem.Close()
+
+	query, err := inMem.EmbeddingVector("king")
+	if err != nil {
+		t.Fatalf("EmbeddingVector(%q): %v", "king", err)
+	}
+
+	// Unlike Similarity and the scan path SearchVSS falls back to here,
+	// MostSimilarK searches the raw HNSW graph
+	// and does not exclude the query's own row, so king itself comes back
+	// as the closest match; man is the closest distinct word.
+	neighbors, err := inMem.MostSimilarK(query, 2)
+	if err != nil {
+		t.Fatalf("MostSimilarK: %v", err)
+	}
+	if len(neighbors) != 2 || neighbors[0].Word != "king" || neighbors[1].Word != "man" {
+		t.Errorf("MostSimilarK(king, 2) = %+v, want [{king ...} {man ...}]", neighbors)
+	}
+
+	vssNeighbors, err := inMem.SearchVSS(query, 1)
+	if err != nil {
+		t.Fatalf("SearchVSS: %v", err)
+	}
+	if len(vssNeighbors) != 1 || vssNeighbors[0].Word != "man" {
+		t.Errorf("SearchVSS(king, 1) = %+v, want [{man ...}]", vssNeighbors)
+	}
+}
@@ -0,0 +1,366 @@
+package fasttext
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// binaryByteOrder is the byte order Facebook's fastText C++ implementation
+// uses when serializing its native .bin model files. It is independent of
+// ByteOrder, which this package uses for its own SQLite BLOB encoding.
+var binaryByteOrder = binary.LittleEndian
+
+// binMagic is the magic number fastText writes at the start of every
+// .bin file it produces (FASTTEXT_FILEFORMAT_MAGIC_INT32 upstream).
+const binMagic int32 = 793712314
+
+// ErrQuantizedModel is returned by BuildFromBinary for quantized
+// (-qnorm/-qout) fastText models, which are not supported.
+var ErrQuantizedModel = errors.New("fasttext: quantized .bin models are not supported")
+
+var errNoMeta = errors.New("fasttext: no subword metadata (model was not built with BuildFromBinary)")
+
+// entryType mirrors fastText's Dictionary::entry_type enum.
+type entryType int8
+
+const (
+	entryWord  entryType = 0
+	entryLabel entryType = 1
+)
+
+// fastTextMeta holds the subword parameters needed to reconstruct OOV
+// embeddings. It is persisted in the fasttext_meta table.
+type fastTextMeta struct {
+	Dim    int
+	Minn   int
+	Maxn   int
+	Bucket int
+}
+
+// binArgs mirrors the subset of fastText's Args that BuildFromBinary needs
+// to parse the rest of the file and reconstruct subword vectors.
+type binArgs struct {
+	Dim, WS, Epoch, MinCount, Neg, WordNgrams int32
+	Loss, Model                               int32
+	Bucket, Minn, Maxn, LRUpdateRate          int32
+	T                                         float64
+}
+
+// BuildFromBinary initializes the SQLite3 database by importing a model
+// trained with Facebook's fastText and saved in its native `.bin` format
+// (see https://fasttext.cc). Unlike Build, the resulting database also
+// stores the model's subword (character n-gram) vectors in a second
+// table, so EmbeddingVector can compute a vector for words that were
+// never seen during training instead of returning ErrNoEmbFound.
+//
+// Quantized models (trained with -qnorm/-qout) are not supported.
+func (ft *FastText) BuildFromBinary(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	var magic, version int32
+	if err := binary.Read(br, binaryByteOrder, &magic); err != nil {
+		return err
+	}
+	if magic != binMagic {
+		return fmt.Errorf("fasttext: not a fastText .bin file (bad magic %d)", magic)
+	}
+	if err := binary.Read(br, binaryByteOrder, &version); err != nil {
+		return err
+	}
+
+	args, err := readBinArgs(br)
+	if err != nil {
+		return err
+	}
+
+	entries, err := readBinDictionary(br)
+	if err != nil {
+		return err
+	}
+
+	var quantInput bool
+	if err := binary.Read(br, binaryByteOrder, &quantInput); err != nil {
+		return err
+	}
+	if quantInput {
+		return ErrQuantizedModel
+	}
+
+	input, err := readBinMatrix(br)
+	if err != nil {
+		return err
+	}
+
+	nwords := 0
+	for _, e := range entries {
+		if e.Type == entryWord {
+			nwords++
+		}
+	}
+	if len(input) != nwords+int(args.Bucket) {
+		return fmt.Errorf("fasttext: input matrix has %d rows, expected %d words + %d subword buckets",
+			len(input), nwords, args.Bucket)
+	}
+
+	if _, err := ft.db.Exec(`
+	CREATE TABLE fasttext(
+		word TEXT UNIQUE,
+		emb BLOB
+	);`); err != nil {
+		return err
+	}
+	if _, err := ft.db.Exec(`
+	CREATE TABLE fasttext_ngrams(
+		hash INTEGER UNIQUE,
+		emb BLOB
+	);`); err != nil {
+		return err
+	}
+	if err := ft.ensureMetaTable(); err != nil {
+		return err
+	}
+
+	wordStmt, err := ft.db.Prepare(`INSERT INTO fasttext(word, emb) VALUES(?, ?);`)
+	if err != nil {
+		return err
+	}
+	defer wordStmt.Close()
+
+	row := 0
+	for _, e := range entries {
+		if e.Type != entryWord {
+			continue
+		}
+		if _, err := wordStmt.Exec(e.Word, vecToBytes(input[row], ByteOrder)); err != nil {
+			return err
+		}
+		row++
+	}
+
+	ngramStmt, err := ft.db.Prepare(`INSERT INTO fasttext_ngrams(hash, emb) VALUES(?, ?);`)
+	if err != nil {
+		return err
+	}
+	defer ngramStmt.Close()
+	for i := 0; i < int(args.Bucket); i++ {
+		if _, err := ngramStmt.Exec(i, vecToBytes(input[nwords+i], ByteOrder)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := ft.db.Exec(`REPLACE INTO fasttext_meta(id, dim, minn, maxn, bucket, l2_normalized) VALUES(0, ?, ?, ?, ?, 0);`,
+		args.Dim, args.Minn, args.Maxn, args.Bucket); err != nil {
+		return err
+	}
+
+	_, err = ft.db.Exec(`CREATE INDEX ind_word ON fasttext(word);`)
+	return err
+}
+
+func readBinArgs(r io.Reader) (*binArgs, error) {
+	var a binArgs
+	fields := []interface{}{
+		&a.Dim, &a.WS, &a.Epoch, &a.MinCount, &a.Neg, &a.WordNgrams,
+		&a.Loss, &a.Model, &a.Bucket, &a.Minn, &a.Maxn, &a.LRUpdateRate,
+	}
+	for _, f := range fields {
+		if err := binary.Read(r, binaryByteOrder, f); err != nil {
+			return nil, err
+		}
+	}
+	if err := binary.Read(r, binaryByteOrder, &a.T); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+type binDictEntry struct {
+	Word  string
+	Count int64
+	Type  entryType
+}
+
+// readBinDictionary parses fastText's serialized Dictionary: its header,
+// one NUL-terminated word/count/type triple per vocabulary entry, and
+// (for newer format versions) a pruning index that this package has no
+// use for but must still consume to reach the matrices that follow.
+func readBinDictionary(r *bufio.Reader) ([]binDictEntry, error) {
+	var size, nwords, nlabels int32
+	var ntokens, pruneIdxSize int64
+	for _, f := range []interface{}{&size, &nwords, &nlabels, &ntokens, &pruneIdxSize} {
+		if err := binary.Read(r, binaryByteOrder, f); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]binDictEntry, size)
+	for i := range entries {
+		word, err := readCString(r)
+		if err != nil {
+			return nil, err
+		}
+		var count int64
+		if err := binary.Read(r, binaryByteOrder, &count); err != nil {
+			return nil, err
+		}
+		var typ entryType
+		if err := binary.Read(r, binaryByteOrder, &typ); err != nil {
+			return nil, err
+		}
+		entries[i] = binDictEntry{Word: word, Count: count, Type: typ}
+	}
+
+	for i := int64(0); i < pruneIdxSize; i++ {
+		var first, second int32
+		if err := binary.Read(r, binaryByteOrder, &first); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(r, binaryByteOrder, &second); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func readCString(r *bufio.Reader) (string, error) {
+	b, err := r.ReadBytes(0)
+	if err != nil {
+		return "", err
+	}
+	return string(b[:len(b)-1]), nil
+}
+
+// readBinMatrix parses one of fastText's serialized (non-quantized)
+// Matrix objects: a row/column count followed by that many row-major
+// float32 values.
+func readBinMatrix(r io.Reader) ([][]float64, error) {
+	var rows, cols int64
+	if err := binary.Read(r, binaryByteOrder, &rows); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binaryByteOrder, &cols); err != nil {
+		return nil, err
+	}
+
+	matrix := make([][]float64, rows)
+	raw := make([]byte, cols*4)
+	for i := range matrix {
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return nil, err
+		}
+		vec := make([]float64, cols)
+		for j := range vec {
+			bits := binaryByteOrder.Uint32(raw[j*4 : j*4+4])
+			vec[j] = float64(math.Float32frombits(bits))
+		}
+		matrix[i] = vec
+	}
+	return matrix, nil
+}
+
+// loadMeta returns the subword parameters persisted by BuildFromBinary,
+// caching them on ft. It returns errNoMeta if the database was built with
+// Build instead, which does not record subword information.
+func (ft *FastText) loadMeta() (*fastTextMeta, error) {
+	if ft.meta != nil {
+		return ft.meta, nil
+	}
+
+	var m fastTextMeta
+	var dim, minn, maxn, bucket sql.NullInt64
+	err := ft.db.QueryRow(`SELECT dim, minn, maxn, bucket FROM fasttext_meta WHERE id = 0;`).
+		Scan(&dim, &minn, &maxn, &bucket)
+	if err == sql.ErrNoRows || isNoSuchTable(err) {
+		return nil, errNoMeta
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Build also writes this row (for l2_normalized) but leaves the
+	// subword columns NULL.
+	if !dim.Valid {
+		return nil, errNoMeta
+	}
+	m.Dim, m.Minn, m.Maxn, m.Bucket = int(dim.Int64), int(minn.Int64), int(maxn.Int64), int(bucket.Int64)
+
+	ft.meta = &m
+	return ft.meta, nil
+}
+
+func isNoSuchTable(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no such table")
+}
+
+// subwordEmbeddingVector reconstructs the embedding of an out-of-vocabulary
+// word as the average of its character n-gram (subword) vectors, the way
+// fastText itself does for words it has never seen.
+func (ft *FastText) subwordEmbeddingVector(word string) ([]float64, error) {
+	meta, err := ft.loadMeta()
+	if err == errNoMeta {
+		return nil, ErrNoEmbFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sum := make([]float64, meta.Dim)
+	count := 0
+	for _, hash := range subwordHashes(word, meta.Minn, meta.Maxn, meta.Bucket) {
+		var binVec []byte
+		err := ft.db.QueryRow(`SELECT emb FROM fasttext_ngrams WHERE hash=?;`, hash).Scan(&binVec)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		vec, err := bytesToVec(binVec, ByteOrder)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range vec {
+			sum[i] += v
+		}
+		count++
+	}
+	if count == 0 {
+		return nil, ErrNoEmbFound
+	}
+	for i := range sum {
+		sum[i] /= float64(count)
+	}
+	return sum, nil
+}
+
+// subwordHashes returns the fastText hash of every character n-gram of
+// word (wrapped in '<' and '>') for n in [minn, maxn], reduced modulo
+// bucket.
+func subwordHashes(word string, minn, maxn, bucket int) []int {
+	if minn == 0 || maxn == 0 || bucket == 0 {
+		return nil
+	}
+	chars := []rune("<" + word + ">")
+	var hashes []int
+	for n := minn; n <= maxn && n <= len(chars); n++ {
+		for i := 0; i+n <= len(chars); i++ {
+			hashes = append(hashes, int(fastTextHash(string(chars[i:i+n]))%uint32(bucket)))
+		}
+	}
+	return hashes
+}
+
+// fastTextHash implements the FNV-like 32-bit hash fastText uses to map
+// character n-grams to subword bucket rows.
+func fastTextHash(s string) uint32 {
+	h := uint32(2166136261)
+	for _, b := range []byte(s) {
+		h = (h ^ uint32(b)) * 16777619
+	}
+	return h
+}
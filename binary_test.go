@@ -0,0 +1,111 @@
+package fasttext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// writeBinModel encodes a minimal synthetic fastText .bin file containing
+// a single word ("cat") and the given number of subword buckets, so
+// BuildFromBinary can be tested without a real trained model.
+func writeBinModel(t *testing.T, dim, minn, maxn, bucket int32) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	write := func(v interface{}) {
+		if err := binary.Write(buf, binaryByteOrder, v); err != nil {
+			t.Fatalf("writing test model: %v", err)
+		}
+	}
+
+	write(binMagic)
+	write(int32(12)) // version
+
+	// args: dim, ws, epoch, minCount, neg, wordNgrams, loss, model, bucket, minn, maxn, lrUpdateRate, t
+	write(dim)
+	write(int32(5))
+	write(int32(5))
+	write(int32(1))
+	write(int32(5))
+	write(int32(1))
+	write(int32(1))
+	write(int32(2))
+	write(bucket)
+	write(minn)
+	write(maxn)
+	write(int32(100))
+	write(float64(0.0001))
+
+	// dictionary: size, nwords, nlabels, ntokens, pruneidx_size
+	write(int32(1))
+	write(int32(1))
+	write(int32(0))
+	write(int64(1))
+	write(int64(0))
+
+	buf.WriteString("cat")
+	buf.WriteByte(0)
+	write(int64(1)) // count
+	write(entryWord)
+
+	// quantInput
+	write(false)
+
+	// input matrix: (nwords + bucket) rows x dim float32
+	write(int64(1 + bucket))
+	write(int64(dim))
+	row := make([]float32, dim)
+	for r := int32(0); r < 1+bucket; r++ {
+		for i := range row {
+			row[i] = float32(r) + float32(i)*0.01
+		}
+		write(row)
+	}
+
+	return buf.Bytes()
+}
+
+func TestBuildFromBinary(t *testing.T) {
+	ft := New(":memory:")
+	defer ft.Close()
+
+	data := writeBinModel(t, 4, 2, 3, 8)
+	if err := ft.BuildFromBinary(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	emb, err := ft.EmbeddingVector("cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emb) != 4 {
+		t.Errorf("expected embedding of length 4, got %d", len(emb))
+	}
+}
+
+func TestEmbeddingVectorSubwordFallback(t *testing.T) {
+	ft := New(":memory:")
+	defer ft.Close()
+
+	data := writeBinModel(t, 4, 2, 3, 8)
+	if err := ft.BuildFromBinary(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	emb, err := ft.EmbeddingVector("cats")
+	if err != nil {
+		t.Fatalf("expected subword fallback to produce an embedding, got error: %v", err)
+	}
+	if len(emb) != 4 {
+		t.Errorf("expected embedding of length 4, got %d", len(emb))
+	}
+}
+
+func TestEmbeddingVectorNoEmbFoundWithoutSubwords(t *testing.T) {
+	ft := build(t)
+	defer ft.Close()
+
+	if _, err := ft.EmbeddingVector("NotExist1"); err != ErrNoEmbFound {
+		t.Error("expected ErrNoEmbFound for a database built without subword vectors")
+	}
+}
@@ -0,0 +1,265 @@
+package fasttext
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BuildOptions configures the batched, resumable import pipeline used by
+// FastText.BuildWithOptions.
+type BuildOptions struct {
+	// BatchSize is the number of rows committed per transaction. Larger
+	// batches commit less often, which is significantly faster for bulk
+	// imports than committing row by row.
+	BatchSize int
+	// Workers sets the capacity of the bounded channel connecting the
+	// parser goroutine to the writer, letting parsing run ahead of the
+	// disk I/O done while committing batches.
+	Workers int
+	// Progress, if set, is called after every committed batch with the
+	// number of rows processed so far (including rows skipped because a
+	// previous, interrupted Build already committed them) and the total
+	// row count read from the file's header line.
+	Progress func(loaded, total int)
+	// L2Normalize, if true, stores every vector pre-normalized to unit
+	// length, so that cosine similarity (used by Similarity and Analogy)
+	// reduces to a plain dot product.
+	L2Normalize bool
+}
+
+// DefaultBuildOptions returns the BuildOptions used by Build.
+func DefaultBuildOptions() BuildOptions {
+	return BuildOptions{BatchSize: 1000, Workers: 4}
+}
+
+// Build initializes the SQLite3 database by importing the word embeddings
+// from the .vec file downloaded from
+// https://github.com/facebookresearch/fastText/blob/master/pretrained-vectors.md
+//
+// It is equivalent to BuildWithOptions with DefaultBuildOptions.
+func (ft *FastText) Build(wordEmbFile io.Reader) error {
+	return ft.BuildWithOptions(wordEmbFile, DefaultBuildOptions())
+}
+
+// BuildWithOptions is like Build, but commits rows in batches, each inside
+// its own transaction, and checkpoints its progress in a fasttext_progress
+// table. Re-running it on the same database after an interruption resumes
+// from the last committed batch instead of re-importing from scratch.
+func (ft *FastText) BuildWithOptions(wordEmbFile io.Reader, opts BuildOptions) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBuildOptions().BatchSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultBuildOptions().Workers
+	}
+
+	if _, err := ft.db.Exec(`
+	CREATE TABLE IF NOT EXISTS fasttext(
+		word TEXT UNIQUE,
+		emb BLOB
+	);`); err != nil {
+		return err
+	}
+	if _, err := ft.db.Exec(`
+	CREATE TABLE IF NOT EXISTS fasttext_progress(
+		id INTEGER PRIMARY KEY CHECK (id = 0),
+		last_line INTEGER
+	);`); err != nil {
+		return err
+	}
+	if err := ft.setL2Normalized(opts.L2Normalize); err != nil {
+		return err
+	}
+
+	var resumeFrom int
+	err := ft.db.QueryRow(`SELECT last_line FROM fasttext_progress WHERE id = 0;`).Scan(&resumeFrom)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	total, rows, done, err := readWordEmbFile(wordEmbFile, opts.Workers)
+	if err != nil {
+		return err
+	}
+	// done signals the producer goroutine to stop sending once we return,
+	// so it can never block forever writing to a channel nobody drains.
+	defer close(done)
+
+	loaded := resumeFrom
+	batch := make([]wordEmbResult, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := ft.commitBatch(batch, opts.L2Normalize); err != nil {
+			return err
+		}
+		loaded = batch[len(batch)-1].Line
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(loaded, total)
+		}
+		return nil
+	}
+
+	for row := range rows {
+		if row.Err != nil {
+			return row.Err
+		}
+		if row.Line <= resumeFrom {
+			continue
+		}
+		batch = append(batch, row)
+		if len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	_, err = ft.db.Exec(`CREATE INDEX IF NOT EXISTS ind_word ON fasttext(word);`)
+	return err
+}
+
+// commitBatch inserts a batch of rows and checkpoints the last committed
+// line number within a single transaction, so a later resumed Build can
+// never observe a batch as written without also observing its checkpoint.
+// If l2Normalize is set, each vector is scaled to unit length before it
+// is stored.
+func (ft *FastText) commitBatch(batch []wordEmbResult, l2Normalize bool) error {
+	tx, err := ft.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO fasttext(word, emb) VALUES(?, ?);`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, row := range batch {
+		vec := row.Vec
+		if l2Normalize {
+			vec = l2Normalized(vec)
+		}
+		if _, err := stmt.Exec(row.Word, vecToBytes(vec, ByteOrder)); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	stmt.Close()
+
+	if _, err := tx.Exec(`REPLACE INTO fasttext_progress(id, last_line) VALUES(0, ?);`,
+		batch[len(batch)-1].Line); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// wordEmbResult is one parsed row of a .vec file, or a parse error that
+// terminates the pipeline. Line is 1-indexed over data rows, i.e. it
+// excludes the header line.
+type wordEmbResult struct {
+	Line int
+	Word string
+	Vec  []float64
+	Err  error
+}
+
+// readWordEmbFile parses word vectors from a fastText .vec file. It reads
+// the header line synchronously, so the caller learns the vocabulary size
+// immediately, then streams the remaining rows from a background
+// goroutine over the returned channel, one per line. Parse errors are
+// sent on the channel rather than panicking, so BuildWithOptions can fail
+// the whole pipeline cleanly.
+//
+// The caller must close the returned done channel once it stops draining
+// out, including on early-return error paths, so the producer goroutine
+// is never left blocked sending into a full, abandoned channel.
+func readWordEmbFile(wordEmbFile io.Reader, bufferSize int) (total int, out <-chan wordEmbResult, done chan<- struct{}, err error) {
+	scanner := bufio.NewScanner(wordEmbFile)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return 0, nil, nil, err
+		}
+		return 0, nil, nil, errors.New("fasttext: empty word embedding file")
+	}
+	header := strings.Split(scanner.Text(), " ")
+	if len(header) != 2 {
+		return 0, nil, nil, fmt.Errorf("fasttext: malformed header line: %q", scanner.Text())
+	}
+	total, err = strconv.Atoi(header[0])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("fasttext: malformed header line: %w", err)
+	}
+	embSize, err := strconv.Atoi(header[1])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("fasttext: malformed header line: %w", err)
+	}
+
+	ch := make(chan wordEmbResult, bufferSize)
+	stop := make(chan struct{})
+	send := func(r wordEmbResult) (ok bool) {
+		select {
+		case ch <- r:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+	go func() {
+		defer close(ch)
+		line := 0
+		for scanner.Scan() {
+			line++
+			data := scanner.Text()
+
+			items := strings.SplitN(data, " ", 2)
+			word := items[0]
+			if word == "" {
+				word = " "
+			}
+			if len(items) < 2 {
+				send(wordEmbResult{Line: line, Err: fmt.Errorf("fasttext: missing vector on line %d for word %q", line, word)})
+				return
+			}
+
+			vecStrs := strings.Split(strings.TrimSpace(items[1]), " ")
+			if len(vecStrs) != embSize {
+				send(wordEmbResult{Line: line, Err: fmt.Errorf(
+					"fasttext: embedding vec size not same: expected %d, got %d. Loc: line %d, word %s",
+					embSize, len(vecStrs), line, word)})
+				return
+			}
+
+			vec := make([]float64, embSize)
+			for i := 0; i < embSize; i++ {
+				v, err := strconv.ParseFloat(vecStrs[i], 64)
+				if err != nil {
+					send(wordEmbResult{Line: line, Err: fmt.Errorf("fasttext: parsing vector on line %d: %w", line, err)})
+					return
+				}
+				vec[i] = v
+			}
+			if !send(wordEmbResult{Line: line, Word: word, Vec: vec}) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			send(wordEmbResult{Err: err})
+		}
+	}()
+	return total, ch, stop, nil
+}
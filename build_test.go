@@ -0,0 +1,117 @@
+package fasttext
+
+import (
+	"strings"
+	"testing"
+)
+
+const testVecFile = `3 2
+a 1 2
+b 3 4
+c 5 6
+`
+
+func TestBuildWithOptionsBatchesAndIndexes(t *testing.T) {
+	ft := New(":memory:")
+	defer ft.Close()
+
+	var progressCalls int
+	opts := BuildOptions{
+		BatchSize: 2,
+		Workers:   1,
+		Progress: func(loaded, total int) {
+			progressCalls++
+			if total != 3 {
+				t.Errorf("expected total of 3, got %d", total)
+			}
+		},
+	}
+	if err := ft.BuildWithOptions(strings.NewReader(testVecFile), opts); err != nil {
+		t.Fatal(err)
+	}
+	if progressCalls == 0 {
+		t.Error("expected Progress to be called at least once")
+	}
+
+	for word, want := range map[string][]float64{
+		"a": {1, 2},
+		"b": {3, 4},
+		"c": {5, 6},
+	} {
+		got, err := ft.EmbeddingVector(word)
+		if err != nil {
+			t.Fatalf("EmbeddingVector(%q): %v", word, err)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("EmbeddingVector(%q) = %v, want %v", word, got, want)
+			}
+		}
+	}
+}
+
+func TestBuildWithOptionsReturnsParseError(t *testing.T) {
+	ft := New(":memory:")
+	defer ft.Close()
+
+	malformed := "3 2\na 1 2\nb 3\nc 5 6\n"
+	opts := BuildOptions{BatchSize: 1, Workers: 1}
+	err := ft.BuildWithOptions(strings.NewReader(malformed), opts)
+	if err == nil {
+		t.Fatal("expected an error for a malformed row, got nil")
+	}
+	if !strings.Contains(err.Error(), "embedding vec size not same") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBuildWithOptionsReturnsCommitError(t *testing.T) {
+	ft := New(":memory:")
+	defer ft.Close()
+
+	// Two duplicate words force commitBatch to fail on a UNIQUE
+	// constraint after the first batch succeeds. The channel buffer
+	// (Workers: 1) is smaller than the remaining rows, so the error is
+	// returned while the producer still has rows left to send.
+	dup := "4 2\na 1 2\na 3 4\nb 5 6\nc 7 8\n"
+	opts := BuildOptions{BatchSize: 1, Workers: 1}
+	if err := ft.BuildWithOptions(strings.NewReader(dup), opts); err == nil {
+		t.Fatal("expected an error for a duplicate word, got nil")
+	}
+}
+
+func TestBuildWithOptionsResumesFromCheckpoint(t *testing.T) {
+	ft := New(":memory:")
+	defer ft.Close()
+
+	// Stopping a running pipeline after its first batch would be awkward
+	// to set up deterministically, so instead run a first Build over a
+	// copy of the file cut off after its first row: it leaves the same
+	// rows and checkpoint in fasttext and fasttext_progress as a Build
+	// interrupted after the first batch.
+	opts := BuildOptions{BatchSize: 1, Workers: 1}
+	first := "3 2\na 1 2\n"
+	if err := ft.BuildWithOptions(strings.NewReader(first), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ft.BuildWithOptions(strings.NewReader(testVecFile), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	for word, want := range map[string][]float64{
+		"a": {1, 2},
+		"b": {3, 4},
+		"c": {5, 6},
+	} {
+		got, err := ft.EmbeddingVector(word)
+		if err != nil {
+			t.Fatalf("EmbeddingVector(%q): %v", word, err)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("EmbeddingVector(%q) = %v, want %v", word, got, want)
+			}
+		}
+	}
+}
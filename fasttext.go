@@ -43,16 +43,13 @@ but takes a few minutes to load the database.
 package fasttext
 
 import (
-	"bufio"
+	"bytes"
 	"database/sql"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/atedja/go-vector"
-	"github.com/gaspiman/cosine_similarity"
-	"io"
-	"strconv"
-	"strings"
+	"github.com/justinfargnoli/go-fasttext/ann"
 )
 
 const (
@@ -76,17 +73,33 @@ var (
 // among multiple threads.
 type FastText struct {
 	db *sql.DB
+
+	// ann and annWords cache the in-memory HNSW index built by BuildANN
+	// (or lazily loaded on first MostSimilarK/MostSimilarWord call).
+	ann      *ann.Graph
+	annWords map[int]string
+
+	// meta caches the subword parameters persisted by BuildFromBinary.
+	meta *fastTextMeta
+
+	// vssAvailable records whether the sqlite-vss extension
+	// was loadable on this session's connection, detected once at New or
+	// NewInMemory. SearchVSS uses it to fall back to the scan-based search
+	// path when the extension isn't present.
+	vssAvailable bool
 }
 
 // New starts a new FastText session given the location
 // of the SQLite3 database file.
 func New(dbFilename string) *FastText {
-	db, err := sql.Open("sqlite3", dbFilename)
+	registerVSSDriver()
+	db, err := sql.Open(vssDriverName, dbFilename)
 	if err != nil {
 		panic(err)
 	}
 	return &FastText{
-		db: db,
+		db:           db,
+		vssAvailable: detectVSS(db),
 	}
 }
 
@@ -96,7 +109,8 @@ func New(dbFilename string) *FastText {
 // an in-memory SQLite3 database in this function, which
 // will take a few miniutes to finish.
 func NewInMemory(dbFilename string) *FastText {
-	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	registerVSSDriver()
+	db, err := sql.Open(vssDriverName, "file::memory:?cache=shared")
 	_, err = db.Exec(fmt.Sprintf(`ATTACH DATABASE '%s' AS disk;`, dbFilename))
 	if err != nil {
 		panic(err)
@@ -109,40 +123,46 @@ func NewInMemory(dbFilename string) *FastText {
 	if err != nil {
 		panic(err)
 	}
-	return &FastText{
-		db: db,
-	}
-}
-
-// Build initialize the SQLite3 database by importing the word embeddings
-// from the .vec file downloaded from
-// https://github.com/facebookresearch/fastText/blob/master/pretrained-vectors.md
-func (ft *FastText) Build(wordEmbFile io.Reader) error {
-	_, err := ft.db.Exec(`
-	CREATE TABLE fasttext(
-		word TEXT UNIQUE,
-		emb BLOB
-	);`)
-	if err != nil {
-		return err
+	if hasTable(db, "disk", "fasttext_ann_nodes") {
+		if _, err := db.Exec(`CREATE TABLE fasttext_ann_nodes AS SELECT * FROM disk.fasttext_ann_nodes;`); err != nil {
+			panic(err)
+		}
+		if _, err := db.Exec(`CREATE TABLE fasttext_ann_meta AS SELECT * FROM disk.fasttext_ann_meta;`); err != nil {
+			panic(err)
+		}
 	}
-	stmt, err := ft.db.Prepare(`INSERT INTO fasttext(word, emb) VALUES(?, ?);`)
-	if err != nil {
-		return err
+	if hasTable(db, "disk", "fasttext_ngrams") {
+		if _, err := db.Exec(`CREATE TABLE fasttext_ngrams AS SELECT * FROM disk.fasttext_ngrams;`); err != nil {
+			panic(err)
+		}
 	}
-	defer stmt.Close()
-	for emb := range readwordEmbdFile(wordEmbFile) {
-		binVec := vecToBytes(emb.Vec, ByteOrder)
-		if _, err := stmt.Exec(emb.Word, binVec); err != nil {
-			return err
+	// fasttext_meta is written by every build path (it also carries the
+	// l2_normalized flag set by plain BuildWithOptions, not just the
+	// subword metadata from BuildFromBinary), so copy it whenever it
+	// exists rather than only alongside fasttext_ngrams.
+	if hasTable(db, "disk", "fasttext_meta") {
+		if _, err := db.Exec(`CREATE TABLE fasttext_meta AS SELECT * FROM disk.fasttext_meta;`); err != nil {
+			panic(err)
 		}
 	}
-	// Indexing on words
-	_, err = ft.db.Exec(`CREATE INDEX ind_word ON fasttext(word);`)
-	if err != nil {
-		return err
+	// vss_fasttext is a virtual table, not an ordinary one, so it cannot be
+	// copied with CREATE TABLE ... AS SELECT; call BuildVSSIndex again on
+	// the in-memory session if SearchVSS needs it.
+	return &FastText{
+		db:           db,
+		vssAvailable: detectVSS(db),
 	}
-	return nil
+}
+
+// hasTable reports whether the named table exists in the given attached
+// schema (e.g. "main" or "disk").
+func hasTable(db *sql.DB, schema, name string) bool {
+	var count int
+	query := fmt.Sprintf(`SELECT count(*) FROM %s.sqlite_master WHERE type='table' AND name=?;`, schema)
+	if err := db.QueryRow(query, name).Scan(&count); err != nil {
+		return false
+	}
+	return count > 0
 }
 
 // Close must be called before finishing using this FastText
@@ -151,17 +171,20 @@ func (ft *FastText) Close() error {
 	return ft.db.Close()
 }
 
-// EmbeddingVector returns the word embedding of the given word.
+// EmbeddingVector returns the word embedding of the given word. If the
+// database was built with BuildFromBinary and word was never seen during
+// training, the vector is reconstructed from its character n-gram
+// (subword) embeddings instead of failing with ErrNoEmbFound.
 func (ft *FastText) EmbeddingVector(word string) ([]float64, error) {
 	var binVec []byte
 	err := ft.db.QueryRow(`SELECT emb FROM fasttext WHERE word=?;`, word).Scan(&binVec)
-	if err == sql.ErrNoRows {
-		return nil, ErrNoEmbFound
+	if err == nil {
+		return bytesToVec(binVec, ByteOrder)
 	}
-	if err != nil {
+	if err != sql.ErrNoRows {
 		panic(err)
 	}
-	return bytesToVec(binVec, ByteOrder)
+	return ft.subwordEmbeddingVector(word)
 }
 
 // AllEmbeddingVectors returns all embedding vectors
@@ -187,36 +210,6 @@ func (ft *FastText) AllEmbeddingVectors() ([][]float64, error) {
 	return allEmbeddings, nil
 }
 
-// MostSimilarEmbeddingVector returns the embedding vector which is most similar to the one passed
-//
-// Errors from FastText.GetAllEmb() and cosine_similarity.Cosine() will be propogated.
-func (ft *FastText) MostSimilarEmbeddingVector(queryEmbedding []float64) ([]float64, float64, error) {
-	embeddings, err := ft.AllEmbeddingVectors()
-	if err != nil {
-		return nil, 0.0, err
-	}
-	var highestSimilarity float64
-	var mostSimilar []float64
-
-	for _, v := range embeddings {
-		if equalEmbeddings(queryEmbedding, v) {
-			continue
-		}
-
-		similarity, err := cosine_similarity.Cosine(queryEmbedding, v)
-		if err != nil {
-			return nil, 0.0, err
-		}
-
-		if similarity > highestSimilarity {
-			highestSimilarity = similarity
-			mostSimilar = v
-		}
-	}
-
-	return mostSimilar, highestSimilarity, nil
-}
-
 // MultiWordEmbeddingVector builds an embedding vector to represent the array of words passed to it.
 //
 // This is done by averaging the embedding vector of each string in the array.
@@ -241,58 +234,43 @@ func (ft *FastText) MultiWordEmbeddingVector(words []string) (vector.Vector, err
 	return vec, nil
 }
 
-type wordEmb struct {
-	Word string
-	Vec  []float64
+// vecToBytes serializes a word embedding vector into a byte slice
+// using the given byte order, ready to be stored as a BLOB in SQLite3.
+func vecToBytes(vec []float64, order binary.ByteOrder) []byte {
+	buf := new(bytes.Buffer)
+	for _, v := range vec {
+		if err := binary.Write(buf, order, v); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
 }
 
-func readwordEmbdFile(wordEmbFile io.Reader) chan *wordEmb {
-	out := make(chan *wordEmb)
-	go func() {
-		defer close(out)
-		scanner := bufio.NewScanner(wordEmbFile)
-		var embSize int
-		var line int
-		for scanner.Scan() {
-			line++
-			data := scanner.Text()
-			if embSize == 0 {
-				var err error
-				embSize, err = strconv.Atoi(strings.Split(data, " ")[1])
-				if err != nil {
-					panic(err)
-				}
-				continue
-			}
-			// Get the word
-			items := strings.SplitN(data, " ", 2)
-			word := items[0]
-			if word == "" {
-				word = " "
-			}
-			// Get the vec
-			vecStrs := strings.Split(strings.TrimSpace(items[1]), " ")
-			if len(vecStrs) != embSize {
-				msg := fmt.Sprintf("Embedding vec size not same: expected %d, got %d. Loc: line %d, word %s",
-					embSize, len(vecStrs), line, word)
-				panic(msg)
-			}
-			vec := make([]float64, embSize)
-			for i := 0; i < embSize; i++ {
-				sf, err := strconv.ParseFloat(vecStrs[i], 64)
-				if err != nil {
-					panic(err)
-				}
-				vec[i] = sf
-			}
-			out <- &wordEmb{
-				Word: word,
-				Vec:  vec,
-			}
+// bytesToVec deserializes a byte slice, as read from a SQLite3 BLOB,
+// back into a word embedding vector using the given byte order.
+func bytesToVec(b []byte, order binary.ByteOrder) ([]float64, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("invalid embedding vector byte length: %d", len(b))
+	}
+	vec := make([]float64, len(b)/8)
+	reader := bytes.NewReader(b)
+	for i := range vec {
+		if err := binary.Read(reader, order, &vec[i]); err != nil {
+			return nil, err
 		}
-		if err := scanner.Err(); err != nil {
-			panic(err)
+	}
+	return vec, nil
+}
+
+// equalEmbeddings reports whether two embedding vectors are identical.
+func equalEmbeddings(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
-	}()
-	return out
+	}
+	return true
 }
@@ -0,0 +1,54 @@
+package fasttext
+
+import "database/sql"
+
+// fasttext_meta holds a single row (id = 0) of metadata about how the
+// database was built: the subword parameters written by BuildFromBinary
+// (dim, minn, maxn, bucket) and whether the stored vectors are
+// L2-normalized. It is shared across build paths so that any of them can
+// be queried without knowing which one was used.
+func (ft *FastText) ensureMetaTable() error {
+	_, err := ft.db.Exec(`
+	CREATE TABLE IF NOT EXISTS fasttext_meta(
+		id INTEGER PRIMARY KEY CHECK (id = 0),
+		dim INTEGER,
+		minn INTEGER,
+		maxn INTEGER,
+		bucket INTEGER,
+		l2_normalized INTEGER
+	);`)
+	return err
+}
+
+// setL2Normalized records whether the embeddings stored in fasttext are
+// L2-normalized, preserving any subword metadata already persisted by
+// BuildFromBinary.
+func (ft *FastText) setL2Normalized(normalized bool) error {
+	if err := ft.ensureMetaTable(); err != nil {
+		return err
+	}
+
+	var l2 int
+	if normalized {
+		l2 = 1
+	}
+	_, err := ft.db.Exec(`
+	INSERT INTO fasttext_meta(id, l2_normalized) VALUES(0, ?)
+	ON CONFLICT(id) DO UPDATE SET l2_normalized = excluded.l2_normalized;`, l2)
+	return err
+}
+
+// isL2Normalized reports whether the embeddings stored in fasttext are
+// L2-normalized, so callers can compute cosine similarity with a plain
+// dot product instead of the full formula.
+func (ft *FastText) isL2Normalized() (bool, error) {
+	var l2 int
+	err := ft.db.QueryRow(`SELECT l2_normalized FROM fasttext_meta WHERE id = 0;`).Scan(&l2)
+	if err == sql.ErrNoRows || isNoSuchTable(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return l2 != 0, nil
+}
@@ -0,0 +1,190 @@
+package fasttext
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/gaspiman/cosine_similarity"
+)
+
+// WordSimilarity is a word and its similarity score to some query
+// embedding, as returned by Similarity and Analogy.
+type WordSimilarity struct {
+	Word  string
+	Score float64
+}
+
+// Similarity returns the k words whose embeddings are nearest to the
+// embedding of word, ranked by decreasing cosine similarity. word itself
+// is excluded from the results.
+func (ft *FastText) Similarity(word string, k int) ([]WordSimilarity, error) {
+	query, err := ft.EmbeddingVector(word)
+	if err != nil {
+		return nil, err
+	}
+	return ft.topKByCosine(query, k, map[string]bool{word: true})
+}
+
+// Analogy returns the k words closest to emb(b) - emb(a) + emb(c), i.e.
+// the words that best complete "a is to b as c is to ?". a, b and c are
+// excluded from the results.
+func (ft *FastText) Analogy(a, b, c string, k int) ([]WordSimilarity, error) {
+	embA, err := ft.EmbeddingVector(a)
+	if err != nil {
+		return nil, err
+	}
+	embB, err := ft.EmbeddingVector(b)
+	if err != nil {
+		return nil, err
+	}
+	embC, err := ft.EmbeddingVector(c)
+	if err != nil {
+		return nil, err
+	}
+
+	query := make([]float64, len(embB))
+	for i := range query {
+		query[i] = embB[i] - embA[i] + embC[i]
+	}
+
+	return ft.topKByCosine(query, k, map[string]bool{a: true, b: true, c: true})
+}
+
+// MostSimilarEmbeddingVector returns the embedding vector which is most similar to the one passed
+//
+// Errors from the underlying scan of the fasttext table are propagated.
+func (ft *FastText) MostSimilarEmbeddingVector(queryEmbedding []float64) ([]float64, float64, error) {
+	candidates, err := ft.topKCandidates(queryEmbedding, 1, nil)
+	if err != nil {
+		return nil, 0.0, err
+	}
+	if len(candidates) == 0 {
+		return nil, 0.0, nil
+	}
+	return candidates[0].vec, candidates[0].score, nil
+}
+
+// topKByCosine is topKCandidates with the embedding vectors stripped out,
+// used by the public Similarity and Analogy APIs.
+func (ft *FastText) topKByCosine(query []float64, k int, exclude map[string]bool) ([]WordSimilarity, error) {
+	candidates, err := ft.topKCandidates(query, k, exclude)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]WordSimilarity, len(candidates))
+	for i, c := range candidates {
+		result[i] = WordSimilarity{Word: c.word, Score: c.score}
+	}
+	return result, nil
+}
+
+// simCandidate is a word, its embedding, and its similarity score to some
+// query vector.
+type simCandidate struct {
+	word  string
+	vec   []float64
+	score float64
+}
+
+// topKCandidates scans every stored embedding and returns the k most
+// similar to query (highest score first), excluding words in exclude and
+// any row whose embedding exactly equals query. It keeps only a bounded
+// min-heap of size k, so memory use does not grow with vocabulary size.
+func (ft *FastText) topKCandidates(query []float64, k int, exclude map[string]bool) ([]simCandidate, error) {
+	l2Normalized, err := ft.isL2Normalized()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := ft.db.Query(`SELECT word, emb FROM fasttext;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	h := &candidateHeap{}
+	heap.Init(h)
+	for rows.Next() {
+		var word string
+		var binVec []byte
+		if err := rows.Scan(&word, &binVec); err != nil {
+			return nil, err
+		}
+		if exclude[word] {
+			continue
+		}
+		vec, err := bytesToVec(binVec, ByteOrder)
+		if err != nil {
+			return nil, err
+		}
+		if equalEmbeddings(query, vec) {
+			continue
+		}
+
+		var score float64
+		if l2Normalized {
+			score = dot(query, vec)
+		} else {
+			score, err = cosine_similarity.Cosine(query, vec)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if h.Len() < k {
+			heap.Push(h, simCandidate{word: word, vec: vec, score: score})
+		} else if h.Len() > 0 && score > (*h)[0].score {
+			heap.Pop(h)
+			heap.Push(h, simCandidate{word: word, vec: vec, score: score})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]simCandidate, h.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(h).(simCandidate)
+	}
+	return result, nil
+}
+
+// candidateHeap is a min-heap of simCandidate ordered by score, so the
+// weakest of the k candidates kept so far is always at the root and can
+// be evicted in O(log k) when a stronger candidate is found.
+type candidateHeap []simCandidate
+
+func (h candidateHeap) Len() int            { return len(h) }
+func (h candidateHeap) Less(i, j int) bool  { return h[i].score < h[j].score }
+func (h candidateHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *candidateHeap) Push(x interface{}) { *h = append(*h, x.(simCandidate)) }
+func (h *candidateHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// dot returns the dot product of a and b.
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// l2Normalized returns vec scaled to unit length. The zero vector is
+// returned unchanged.
+func l2Normalized(vec []float64) []float64 {
+	norm := math.Sqrt(dot(vec, vec))
+	if norm == 0 {
+		return vec
+	}
+	normalized := make([]float64, len(vec))
+	for i, v := range vec {
+		normalized[i] = v / norm
+	}
+	return normalized
+}
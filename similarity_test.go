@@ -0,0 +1,99 @@
+package fasttext
+
+import (
+	"strings"
+	"testing"
+)
+
+// A small 2D vocabulary laid out so that "queen" - "king" + "man" points
+// almost exactly at "woman", and every word is at a distinct angle from
+// the others, making the expected nearest neighbors unambiguous.
+const analogyVecFile = `5 2
+king 1 0
+queen 0 1
+man 1 0.01
+woman 0.01 1
+rock -1 -1
+`
+
+func buildAnalogy(t *testing.T, opts BuildOptions) *FastText {
+	t.Helper()
+	ft := New(":memory:")
+	if err := ft.BuildWithOptions(strings.NewReader(analogyVecFile), opts); err != nil {
+		t.Fatal(err)
+	}
+	return ft
+}
+
+func TestSimilarityExcludesQueryWord(t *testing.T) {
+	ft := buildAnalogy(t, DefaultBuildOptions())
+	defer ft.Close()
+
+	results, err := ft.Similarity("king", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Word == "king" {
+			t.Errorf("expected Similarity to exclude the query word, got %+v", results)
+		}
+	}
+	if len(results) != 4 {
+		t.Errorf("expected 4 results, got %d", len(results))
+	}
+}
+
+func TestAnalogyExcludesInputWordsAndFindsWoman(t *testing.T) {
+	ft := buildAnalogy(t, DefaultBuildOptions())
+	defer ft.Close()
+
+	results, err := ft.Analogy("king", "queen", "man", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Fatal("expected at least one result")
+	}
+	if results[0].Word != "woman" {
+		t.Errorf("expected top analogy result to be %q, got %q", "woman", results[0].Word)
+	}
+	for _, r := range results {
+		if r.Word == "king" || r.Word == "queen" || r.Word == "man" {
+			t.Errorf("expected Analogy to exclude its input words, got %+v", results)
+		}
+	}
+}
+
+func TestMostSimilarEmbeddingVectorUsesHeapScan(t *testing.T) {
+	ft := buildAnalogy(t, DefaultBuildOptions())
+	defer ft.Close()
+
+	queryEmb, err := ft.EmbeddingVector("king")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vec, score, err := ft.MostSimilarEmbeddingVector(queryEmb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vec == nil {
+		t.Fatal("expected a non-nil most similar vector")
+	}
+	if score <= 0 {
+		t.Errorf("expected a positive similarity score, got %f", score)
+	}
+}
+
+func TestSimilarityWithL2Normalize(t *testing.T) {
+	ft := buildAnalogy(t, BuildOptions{BatchSize: 1000, Workers: 1, L2Normalize: true})
+	defer ft.Close()
+
+	results, err := ft.Similarity("king", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Word != "man" {
+		t.Errorf("expected closest neighbor of king to be man, got %+v", results)
+	}
+}
@@ -0,0 +1,186 @@
+package fasttext
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// vssDriverName is the database/sql driver name this package registers
+// for itself, distinct from the plain "sqlite3" driver name a caller may
+// have registered via a blank import, so that New and NewInMemory always
+// get a connection whose ConnectHook attempts to load sqlite-vss.
+const vssDriverName = "sqlite3_with_vss"
+
+var registerVSSDriverOnce sync.Once
+
+// registerVSSDriver registers vssDriverName, a sqlite3 driver that tries
+// to load the sqlite-vss extension ("vss0") on every new connection; the
+// sqlite-vec extension is not tried. Loading is best-effort: most SQLite builds don't ship the
+// extension, and FastText falls back to its scan-based search path when
+// it isn't available, so a failure here is silently ignored rather than
+// surfaced as a connection error.
+func registerVSSDriver() {
+	registerVSSDriverOnce.Do(func() {
+		sql.Register(vssDriverName, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				conn.LoadExtension("vss0", "")
+				return nil
+			},
+		})
+	})
+}
+
+// detectVSS reports whether the sqlite-vss extension loaded successfully
+// on db's connection.
+func detectVSS(db *sql.DB) bool {
+	var version string
+	return db.QueryRow(`SELECT vss_version();`).Scan(&version) == nil
+}
+
+// BuildVSSIndex creates a sqlite-vss virtual table over the embeddings
+// already loaded by Build, so SearchVSS can use SQLite's native vector
+// search instead of scanning every row in Go. It is a no-op, returning
+// nil, if the vss extension was not loadable for this session -
+// SearchVSS transparently falls back to the scan-based path in that case.
+func (ft *FastText) BuildVSSIndex() error {
+	if !ft.vssAvailable {
+		return nil
+	}
+
+	dim, err := ft.vectorWidth()
+	if err != nil {
+		return err
+	}
+
+	if _, err := ft.db.Exec(fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS vss_fasttext USING vss0(emb(%d));`, dim)); err != nil {
+		return err
+	}
+
+	rows, err := ft.db.Query(`SELECT rowid, emb FROM fasttext;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	stmt, err := ft.db.Prepare(`INSERT INTO vss_fasttext(rowid, emb) VALUES(?, ?);`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for rows.Next() {
+		var rowid int64
+		var binVec []byte
+		if err := rows.Scan(&rowid, &binVec); err != nil {
+			return err
+		}
+		vec, err := bytesToVec(binVec, ByteOrder)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(rowid, vssVectorBytes(vec)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SearchVSS returns the k embeddings nearest to query. If the sqlite-vss
+// extension was loadable for this session and BuildVSSIndex has been
+// called, the search runs inside SQLite via vss_search; otherwise it
+// transparently falls back to the same heap-based scan Similarity uses,
+// so callers get one API regardless of deployment.
+func (ft *FastText) SearchVSS(query []float64, k int) ([]Neighbor, error) {
+	if !ft.vssAvailable {
+		return ft.searchVSSFallback(query, k)
+	}
+
+	rows, err := ft.db.Query(`
+	SELECT fasttext.word, vss_fasttext.distance
+	FROM vss_fasttext
+	JOIN fasttext ON fasttext.rowid = vss_fasttext.rowid
+	WHERE vss_search(vss_fasttext.emb, vss_search_params(?, ?))
+	ORDER BY vss_fasttext.distance;`, vssVectorBytes(query), k)
+	if err != nil {
+		if isNoSuchTable(err) {
+			// BuildVSSIndex was never called on this database.
+			return ft.searchVSSFallback(query, k)
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var neighbors []Neighbor
+	for rows.Next() {
+		var word string
+		var distance float64
+		if err := rows.Scan(&word, &distance); err != nil {
+			return nil, err
+		}
+		// Neighbor.Score is "higher is closer" everywhere else in this
+		// package, so negate the distance vss_fasttext returns.
+		neighbors = append(neighbors, Neighbor{Word: word, Score: -distance})
+	}
+	return neighbors, rows.Err()
+}
+
+// searchVSSFallback answers SearchVSS with the same bounded-heap cosine
+// scan Similarity and Analogy use.
+func (ft *FastText) searchVSSFallback(query []float64, k int) ([]Neighbor, error) {
+	results, err := ft.topKByCosine(query, k, nil)
+	if err != nil {
+		return nil, err
+	}
+	neighbors := make([]Neighbor, len(results))
+	for i, r := range results {
+		neighbors[i] = Neighbor{Word: r.Word, Score: r.Score}
+	}
+	return neighbors, nil
+}
+
+// vectorWidth returns the dimensionality of the vectors stored in
+// fasttext, used to size the vss0 virtual table. It prefers the
+// fasttext_meta.dim recorded by BuildFromBinary, since that is known
+// without reading any row, and falls back to the length of the first
+// stored vector for databases built with Build/BuildWithOptions, which
+// does not record a dimension.
+func (ft *FastText) vectorWidth() (int, error) {
+	var dim sql.NullInt64
+	err := ft.db.QueryRow(`SELECT dim FROM fasttext_meta WHERE id = 0;`).Scan(&dim)
+	if err != nil && err != sql.ErrNoRows && !isNoSuchTable(err) {
+		return 0, err
+	}
+	if dim.Valid && dim.Int64 > 0 {
+		return int(dim.Int64), nil
+	}
+
+	var binVec []byte
+	err = ft.db.QueryRow(`SELECT emb FROM fasttext LIMIT 1;`).Scan(&binVec)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("fasttext: cannot determine vector width of an empty fasttext table")
+	}
+	if err != nil {
+		return 0, err
+	}
+	vec, err := bytesToVec(binVec, ByteOrder)
+	if err != nil {
+		return 0, err
+	}
+	return len(vec), nil
+}
+
+// vssVectorBytes serializes a vector into the little-endian float32 blob
+// format the vss0 virtual table module expects.
+func vssVectorBytes(vec []float64) []byte {
+	buf := make([]byte, len(vec)*4)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(v)))
+	}
+	return buf
+}
@@ -0,0 +1,61 @@
+package fasttext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVectorWidthFallsBackToStoredVectorLength(t *testing.T) {
+	ft := New(":memory:")
+	defer ft.Close()
+
+	// analogyVecFile is 2-dimensional and built with BuildWithOptions, so
+	// no fasttext_meta.dim is recorded; vectorWidth must fall back to the
+	// width of a stored vector rather than assuming the package-wide
+	// 300-dim default, which would size vss_fasttext incorrectly.
+	if err := ft.BuildWithOptions(strings.NewReader(analogyVecFile), DefaultBuildOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ft.vectorWidth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 2 {
+		t.Errorf("vectorWidth() = %d, want 2", got)
+	}
+}
+
+func TestSearchVSSFallsBackWithoutExtension(t *testing.T) {
+	ft := New(":memory:")
+	defer ft.Close()
+
+	if ft.vssAvailable {
+		t.Skip("sqlite-vss extension is loaded in this environment; fallback path not exercised")
+	}
+
+	if err := ft.BuildWithOptions(strings.NewReader(analogyVecFile), DefaultBuildOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	// BuildVSSIndex must be a harmless no-op when the extension isn't
+	// available, rather than failing because vss_fasttext can't be created.
+	if err := ft.BuildVSSIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := ft.EmbeddingVector("king")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	neighbors, err := ft.SearchVSS(query, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The scan path excludes rows whose embedding exactly equals the
+	// query, so the nearest neighbor of king's own vector is man, not king.
+	if len(neighbors) != 1 || neighbors[0].Word != "man" {
+		t.Errorf("expected SearchVSS to fall back to the scan path and find man, got %+v", neighbors)
+	}
+}